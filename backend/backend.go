@@ -0,0 +1,170 @@
+// Copyright 2026 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+// Package backend defines a provider-agnostic interface for weather data
+// sources. Concrete sources, such as weathergov, implement the Backend
+// interface and register themselves with RegisterBackend so that they can be
+// selected by name, eg. from a configuration file.
+package backend
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OpaqueCloudCoverage represents the cloud coverage as a fraction of the sky
+// as defined at https://www.weather.gov/bgm/forecast_terms. It is the common
+// scale that every Backend implementation normalizes its provider specific
+// condition strings into.
+type OpaqueCloudCoverage int
+
+const (
+	UnknownOpaqueCloudCoverage OpaqueCloudCoverage = iota
+	ClearSunny                                     // 0 to 1/8 Opaque Cloud Coverage
+	MostlyClearSunny                               // 1/8 to 3/8
+	PartlyCloudySunny                              // 3/8 to 5/8
+	MostlyCloudy                                   // 5/8 to 7/8
+	Cloudy                                         // 7/8 to 8/8
+	Rain
+	Snow
+)
+
+// Period represents the forecast for a single period of time, normalized
+// across backends.
+type Period struct {
+	StartTime                  time.Time
+	EndTime                    time.Time
+	Name                       string
+	ShortForecast              string
+	DetailedForecast           string
+	OpaqueCloudCoverage        OpaqueCloudCoverage
+	IsDaytime                  bool
+	Temperature                float64
+	TemperatureUnit            string
+	TemperatureTrend           string
+	WindSpeed                  string
+	WindDirection              string
+	ProbabilityOfPrecipitation float64
+	DewPoint                   float64
+	RelativeHumidity           float64
+}
+
+// Location represents the location metadata, including reverse-geocoded
+// city/state, that a Backend was able to resolve for the coordinates it was
+// asked to fetch a Forecast for. Fields are left at their zero value when
+// the backend does not support, or could not resolve, that piece of
+// metadata.
+type Location struct {
+	City           string
+	State          string
+	GridID         string
+	TimeZone       string
+	ForecastZoneID string
+	CountyID       string
+}
+
+// Forecast represents the forecasts returned by a Backend for a given
+// location. HourlyPeriods is populated in addition to Periods when the
+// backend supports, and has been asked to return, hour granular forecasts.
+type Forecast struct {
+	Lat           float64
+	Long          float64
+	Location      Location
+	Periods       []Period
+	HourlyPeriods []Period
+}
+
+// CloudOpacityFromShortForecast returns the OpaqueCloudCoverage that best
+// matches the supplied short forecast string, using the NWS forecast
+// terminology (https://www.weather.gov/bgm/forecast_terms) as the common
+// vocabulary that backends normalize their own condition strings into.
+func CloudOpacityFromShortForecast(shortForecast string) OpaqueCloudCoverage {
+	tl := strings.ToLower(shortForecast)
+	switch {
+	case strings.HasPrefix(tl, "mostly clear"), strings.HasPrefix(tl, "mostly sunny"):
+		return MostlyClearSunny
+	case strings.HasPrefix(tl, "partly cloudy"), strings.HasPrefix(tl, "partly sunny"):
+		return PartlyCloudySunny
+	case strings.HasPrefix(tl, "mostly cloudy"):
+		return MostlyCloudy
+	case strings.HasPrefix(tl, "clear"), strings.HasPrefix(tl, "sunny"):
+		return ClearSunny
+	case strings.HasPrefix(tl, "cloudy"):
+		return Cloudy
+	case strings.Contains(tl, "rain"):
+		return Rain
+	case strings.Contains(tl, "snow"):
+		return Snow
+	}
+	return UnknownOpaqueCloudCoverage
+}
+
+// ForTime returns the period that covers the supplied time.
+func (fc Forecast) ForTime(when time.Time) (Period, bool) {
+	return periodFor(fc.Periods, when)
+}
+
+// PeriodFor returns the period that covers the supplied time, preferring the
+// more granular HourlyPeriods over Periods when both cover that time.
+func (fc Forecast) PeriodFor(when time.Time) (Period, bool) {
+	if p, ok := periodFor(fc.HourlyPeriods, when); ok {
+		return p, true
+	}
+	return periodFor(fc.Periods, when)
+}
+
+func periodFor(periods []Period, when time.Time) (Period, bool) {
+	for _, p := range periods {
+		if !p.StartTime.After(when) && p.EndTime.After(when) {
+			return p, true
+		}
+	}
+	return Period{}, false
+}
+
+// Backend is implemented by every weather data source that can be plugged
+// into the weatherdev device. Configure is called once, immediately after
+// the backend is created by its factory, with the backend specific options
+// taken from the device's configuration.
+type Backend interface {
+	// Name returns the name that the backend was registered under.
+	Name() string
+	// Fetch returns the forecast for the given location for the requested
+	// number of days.
+	Fetch(ctx context.Context, lat, long float64, days int) (Forecast, error)
+	// Configure applies backend specific configuration options.
+	Configure(options map[string]any) error
+}
+
+// Factory creates a new, unconfigured, instance of a Backend.
+type Factory func() Backend
+
+var (
+	mu       sync.Mutex
+	registry = map[string]Factory{}
+)
+
+// RegisterBackend registers a factory for the named backend. It is expected
+// to be called from the init function of the package implementing the
+// backend.
+func RegisterBackend(name string, factory Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+	registry[name] = factory
+}
+
+// NewBackend creates a new instance of the named backend using the factory
+// supplied to RegisterBackend.
+func NewBackend(name string) (Backend, error) {
+	mu.Lock()
+	factory, ok := registry[name]
+	mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("unsupported weather backend: %q", name)
+	}
+	return factory(), nil
+}
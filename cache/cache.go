@@ -0,0 +1,133 @@
+// Copyright 2026 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+// Package cache provides a small, pluggable, cache for the responses
+// returned by weather backends, keyed by location and endpoint. It exists
+// so that backends can avoid redundant network round trips and respect the
+// TTLs (Expires/Cache-Control) returned by the upstream API, rather than
+// re-fetching, or unconditionally persisting, a response on every call.
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Key identifies a cached response by the location and endpoint it was
+// fetched for.
+type Key struct {
+	Lat, Long float64
+	Endpoint  string
+}
+
+// Entry is a single cached response body together with the time at which
+// it should no longer be served from the cache.
+type Entry struct {
+	Body    []byte
+	Expires time.Time
+}
+
+// Expired returns true if this entry is past its Expires time. An entry
+// with a zero Expires never expires.
+func (e Entry) Expired() bool {
+	return !e.Expires.IsZero() && time.Now().After(e.Expires)
+}
+
+// Cache is implemented by the storage backends used to cache responses.
+// Get returns false if there is no entry for key, or if that entry has
+// expired.
+type Cache interface {
+	Get(key Key) (Entry, bool)
+	Set(key Key, entry Entry)
+}
+
+// Memory is a Cache implementation that stores entries in memory; entries
+// do not survive process restarts.
+type Memory struct {
+	mu      sync.Mutex
+	entries map[Key]Entry
+}
+
+// NewMemory creates a new, empty, in-memory Cache.
+func NewMemory() *Memory {
+	return &Memory{entries: map[Key]Entry{}}
+}
+
+func (m *Memory) Get(key Key) (Entry, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entries[key]
+	if !ok || e.Expired() {
+		return Entry{}, false
+	}
+	return e, true
+}
+
+func (m *Memory) Set(key Key, entry Entry) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries[key] = entry
+}
+
+// Filesystem is a Cache implementation that persists entries as files
+// under a base directory, so that the cache survives process restarts.
+type Filesystem struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewFilesystem creates a new Filesystem cache that stores its entries
+// under dir, which is created, along with any missing parents, on first
+// use.
+func NewFilesystem(dir string) *Filesystem {
+	return &Filesystem{dir: dir}
+}
+
+// filesystemEntry is the on-disk representation of an Entry.
+type filesystemEntry struct {
+	Expires time.Time `json:"expires"`
+	Body    []byte    `json:"body"`
+}
+
+func (f *Filesystem) pathFor(key Key) string {
+	endpoint := strings.ReplaceAll(key.Endpoint, "/", "_")
+	name := fmt.Sprintf("%.4f,%.4f-%s.json", key.Lat, key.Long, endpoint)
+	return filepath.Join(f.dir, name)
+}
+
+func (f *Filesystem) Get(key Key) (Entry, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	data, err := os.ReadFile(f.pathFor(key))
+	if err != nil {
+		return Entry{}, false
+	}
+	var fe filesystemEntry
+	if err := json.Unmarshal(data, &fe); err != nil {
+		return Entry{}, false
+	}
+	entry := Entry{Body: fe.Body, Expires: fe.Expires}
+	if entry.Expired() {
+		return Entry{}, false
+	}
+	return entry, true
+}
+
+func (f *Filesystem) Set(key Key, entry Entry) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := os.MkdirAll(f.dir, 0o755); err != nil {
+		return
+	}
+	data, err := json.Marshal(filesystemEntry{Expires: entry.Expires, Body: entry.Body})
+	if err != nil {
+		return
+	}
+	_ = os.WriteFile(f.pathFor(key), data, 0o644)
+}
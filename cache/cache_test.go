@@ -0,0 +1,42 @@
+// Copyright 2026 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package cache_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cosnicolaou/weather/cache"
+)
+
+func testCache(t *testing.T, c cache.Cache) {
+	key := cache.Key{Lat: 1, Long: 2, Endpoint: "forecast"}
+	if _, ok := c.Get(key); ok {
+		t.Fatalf("expected no entry for an unset key")
+	}
+
+	c.Set(key, cache.Entry{Body: []byte("hello"), Expires: time.Now().Add(time.Hour)})
+	entry, ok := c.Get(key)
+	if !ok {
+		t.Fatalf("expected an entry to be found")
+	}
+	if got, want := string(entry.Body), "hello"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	expired := cache.Key{Lat: 3, Long: 4, Endpoint: "forecast"}
+	c.Set(expired, cache.Entry{Body: []byte("stale"), Expires: time.Now().Add(-time.Hour)})
+	if _, ok := c.Get(expired); ok {
+		t.Errorf("expected an expired entry to not be found")
+	}
+}
+
+func TestMemory(t *testing.T) {
+	testCache(t, cache.NewMemory())
+}
+
+func TestFilesystem(t *testing.T) {
+	testCache(t, cache.NewFilesystem(t.TempDir()))
+}
@@ -0,0 +1,110 @@
+// Copyright 2026 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package weatherdev
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/cosnicolaou/automation/devices"
+	"github.com/cosnicolaou/weather/weathergov"
+)
+
+// AlertsConfig is the device specific configuration for an Alerts device.
+type AlertsConfig struct{}
+
+// Alerts is a devices.Device that exposes the active NWS weather alerts for
+// the system location as a set of conditions that can be used to trigger
+// automation, eg. retracting awnings or closing skylights ahead of severe
+// weather.
+type Alerts struct {
+	devices.DeviceBase[AlertsConfig]
+	service *Service
+}
+
+// NewAlerts creates a new, uninitialized, Alerts device.
+func NewAlerts(_ devices.Options) *Alerts {
+	return &Alerts{}
+}
+
+func (a *Alerts) Implementation() any {
+	return a
+}
+
+func (a *Alerts) SetController(c devices.Controller) {
+	a.service = c.Implementation().(*Service)
+}
+
+func (a *Alerts) ControlledBy() devices.Controller {
+	return a.service
+}
+
+func (a *Alerts) Conditions() map[string]devices.Condition {
+	return map[string]devices.Condition{
+		"alert-active":            a.AlertActive,
+		"alert-severity-at-least": a.AlertSeverityAtLeast,
+		"alert-event-matches":     a.AlertEventMatches,
+	}
+}
+
+func (a *Alerts) ConditionsHelp() map[string]string {
+	return map[string]string{
+		"alert-active":            "returns true if there is at least one active alert",
+		"alert-severity-at-least": "returns true if the highest severity amongst the active alerts is at least that specified, one of Minor, Moderate, Severe or Extreme",
+		"alert-event-matches":     "returns true if the event of any active alert matches the supplied string, eg. \"Tornado Warning\"",
+	}
+}
+
+// AlertActive returns true if there is at least one active alert.
+func (a *Alerts) AlertActive(ctx context.Context, opts devices.OperationArgs) (any, bool, error) {
+	alerts, err := a.service.Alerts(ctx, opts)
+	if err != nil {
+		return nil, false, err
+	}
+	return alerts, len(alerts) > 0, nil
+}
+
+// AlertSeverityAtLeast returns true if the highest severity amongst the
+// active alerts is at least that specified by the condition's argument.
+func (a *Alerts) AlertSeverityAtLeast(ctx context.Context, opts devices.OperationArgs) (any, bool, error) {
+	if len(opts.Args) != 1 {
+		return nil, false, fmt.Errorf("expected a single severity argument, one of Minor, Moderate, Severe or Extreme")
+	}
+	wanted := weathergov.SeverityFromString(opts.Args[0])
+	if wanted == weathergov.UnknownSeverity {
+		return nil, false, fmt.Errorf("unknown severity: %q, expected one of Minor, Moderate, Severe or Extreme", opts.Args[0])
+	}
+	alerts, err := a.service.Alerts(ctx, opts)
+	if err != nil {
+		return nil, false, err
+	}
+	highest := weathergov.UnknownSeverity
+	for _, al := range alerts {
+		if al.Severity > highest {
+			highest = al.Severity
+		}
+	}
+	return highest, highest >= wanted, nil
+}
+
+// AlertEventMatches returns true if the event of any active alert matches
+// the string supplied as the condition's argument, eg. "Tornado Warning".
+func (a *Alerts) AlertEventMatches(ctx context.Context, opts devices.OperationArgs) (any, bool, error) {
+	if len(opts.Args) != 1 {
+		return nil, false, fmt.Errorf("expected a single event argument, eg. %q", "Tornado Warning")
+	}
+	wanted := strings.ToLower(opts.Args[0])
+	alerts, err := a.service.Alerts(ctx, opts)
+	if err != nil {
+		return nil, false, err
+	}
+	for _, al := range alerts {
+		if strings.ToLower(al.Event) == wanted {
+			return al.Event, true, nil
+		}
+	}
+	return nil, false, nil
+}
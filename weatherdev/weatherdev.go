@@ -10,12 +10,14 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
-	"cloudeng.io/webapi/clients/nws"
 	"github.com/cosnicolaou/automation/devices"
+	"github.com/cosnicolaou/weather/backend"
+	"github.com/cosnicolaou/weather/weathergov"
 )
 
 func NewController(typ string, opts devices.Options) (devices.Controller, error) {
@@ -26,8 +28,11 @@ func NewController(typ string, opts devices.Options) (devices.Controller, error)
 }
 
 func NewDevice(typ string, opts devices.Options) (devices.Device, error) {
-	if typ == "forecast" {
+	switch typ {
+	case "forecast":
 		return NewForecast(opts), nil
+	case "alerts":
+		return NewAlerts(opts), nil
 	}
 	return nil, fmt.Errorf("unsupported weather device %s", typ)
 }
@@ -35,6 +40,7 @@ func NewDevice(typ string, opts devices.Options) (devices.Device, error) {
 func SupportedDevices() devices.SupportedDevices {
 	return devices.SupportedDevices{
 		"forecast": NewDevice,
+		"alerts":   NewDevice,
 	}
 }
 
@@ -45,15 +51,35 @@ func SupportedControllers() devices.SupportedControllers {
 }
 
 type ServiceConfig struct {
-	Refresh time.Duration `yaml:"refresh_interval"`
+	Refresh        time.Duration  `yaml:"refresh_interval"`
+	Backend        string         `yaml:"backend"`
+	BackendOptions map[string]any `yaml:"backend_options"`
+	// AutoTimeZone causes the system location's TimeLocation to be filled
+	// in, from the IANA time zone resolved for that location by the
+	// backend, the first time a forecast is fetched. It has no effect if
+	// the backend does not resolve a time zone, or if the time zone cannot
+	// be loaded.
+	AutoTimeZone bool `yaml:"auto_time_zone"`
+	// PrefetchInterval, when set, starts a background goroutine that calls
+	// Forecasts for the system location at that interval. Those calls go
+	// through the backend's cache, so an entry that has not yet expired is
+	// served from the cache rather than refreshed; an expired entry is
+	// re-fetched on the first tick after it expires, and a Forecasts call
+	// made between that expiry and the tick waits on a network round trip.
+	// It is opt-in since not every backend configuration benefits from it,
+	// eg. one without a persistent cache configured.
+	PrefetchInterval time.Duration `yaml:"prefetch_interval"`
 }
 
 type Service struct {
 	devices.ControllerBase[ServiceConfig]
 	logger *slog.Logger
 
-	mu  sync.Mutex
-	api *nws.API
+	mu           sync.Mutex
+	api          backend.Backend
+	alerts       *weathergov.Alerts
+	locateOnce   sync.Once
+	prefetchOnce sync.Once
 }
 
 func NewService(opts devices.Options) *Service {
@@ -63,65 +89,207 @@ func NewService(opts devices.Options) *Service {
 	return c
 }
 
-func (s *Service) SetNWSAPI(api *nws.API) {
+// SetBackend overrides the backend used by this service, primarily for
+// testing. It is normally created on demand from the configured backend:
+// name.
+func (s *Service) SetBackend(b backend.Backend) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.api = api
+	s.api = b
+}
+
+// SetAlerts overrides the alerts client used by this service, primarily for
+// testing. It is normally created on demand, using the same host as the
+// configured backend's "host" option, if any.
+func (s *Service) SetAlerts(a *weathergov.Alerts) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.alerts = a
 }
 
 func (s *Service) Operations() map[string]devices.Operation {
 	return map[string]devices.Operation{
 		"forecast": s.forecasts,
+		"alerts":   s.activeAlerts,
 	}
 }
 
 func (s *Service) OperationsHelp() map[string]string {
 	return map[string]string{
 		"forecast": "get the weather forecast for the system location that the controller belongs to",
+		"alerts":   "get the active weather alerts for the system location that the controller belongs to",
 	}
 }
 
-func (s *Service) forecasts(ctx context.Context, opts devices.OperationArgs) error {
+func (s *Service) forecasts(ctx context.Context, opts devices.OperationArgs) (any, error) {
 	fc, err := s.Forecasts(ctx, opts)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	out, err := json.MarshalIndent(fc, "", "  ")
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if opts.Writer != nil {
+		if _, err := opts.Writer.Write(out); err != nil {
+			return nil, err
+		}
+	}
+	return fc, nil
+}
+
+func (s *Service) activeAlerts(ctx context.Context, opts devices.OperationArgs) (any, error) {
+	alerts, err := s.Alerts(ctx, opts)
+	if err != nil {
+		return nil, err
 	}
-	_, err = opts.Writer.Write(out)
-	return err
+	out, err := json.MarshalIndent(alerts, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if opts.Writer != nil {
+		if _, err := opts.Writer.Write(out); err != nil {
+			return nil, err
+		}
+	}
+	return alerts, nil
 }
 
-func (s *Service) getAPI() *nws.API {
+func (s *Service) getAlerts() *weathergov.Alerts {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	if s.api == nil {
-		s.api = nws.NewAPI(nws.WithForecastExpiration(s.ControllerConfigCustom.Refresh))
+	if s.alerts == nil {
+		a := weathergov.NewAlerts()
+		if host, ok := s.ControllerConfigCustom.BackendOptions["host"].(string); ok {
+			a.SetHost(host)
+		}
+		s.alerts = a
 	}
-	return s.api
+	return s.alerts
 }
 
-func (s *Service) Forecasts(ctx context.Context, _ devices.OperationArgs) (nws.Forecast, error) {
-	api := s.getAPI()
+// Alerts returns the weather alerts currently active for the system
+// location that this controller belongs to.
+func (s *Service) Alerts(ctx context.Context, _ devices.OperationArgs) ([]weathergov.Alert, error) {
 	loc := s.System().Location
-	gp, err := api.LookupGridPoints(ctx, loc.Latitude, loc.Longitude)
+	return s.getAlerts().GetActiveAlerts(ctx, loc.Latitude, loc.Longitude)
+}
+
+// WatchAlerts subscribes to changes in the weather alerts active for the
+// system location that this controller belongs to. See
+// weathergov.Alerts.WatchAlerts for delivery semantics.
+func (s *Service) WatchAlerts(ctx context.Context) <-chan weathergov.Alert {
+	loc := s.System().Location
+	return s.getAlerts().WatchAlerts(ctx, loc.Latitude, loc.Longitude)
+}
+
+func (s *Service) getBackend() (backend.Backend, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.api == nil {
+		name := s.ControllerConfigCustom.Backend
+		if name == "" {
+			name = "weather.gov"
+		}
+		b, err := backend.NewBackend(name)
+		if err != nil {
+			return nil, err
+		}
+		if err := b.Configure(s.ControllerConfigCustom.BackendOptions); err != nil {
+			return nil, fmt.Errorf("failed to configure %s backend: %w", name, err)
+		}
+		s.api = b
+	}
+	return s.api, nil
+}
+
+func (s *Service) Forecasts(ctx context.Context, _ devices.OperationArgs) (backend.Forecast, error) {
+	api, err := s.getBackend()
 	if err != nil {
-		return nws.Forecast{}, err
+		return backend.Forecast{}, err
 	}
-	fc, err := api.GetForecasts(ctx, gp)
+	loc := s.System().Location
+	fc, err := api.Fetch(ctx, loc.Latitude, loc.Longitude, 0)
 	if err != nil {
-		return nws.Forecast{}, err
+		return backend.Forecast{}, err
 	}
+	s.locateOnce.Do(func() { s.resolveLocation(fc.Location) })
 	return fc, nil
 }
 
+// resolveLocation logs the reverse-geocoded city/state for the system
+// location, and, if AutoTimeZone is set, fills in the system location's
+// TimeLocation from the resolved IANA time zone.
+func (s *Service) resolveLocation(loc backend.Location) {
+	if loc.City != "" || loc.State != "" {
+		s.logger.Info("resolved weather location", "city", loc.City, "state", loc.State)
+	}
+	if !s.ControllerConfigCustom.AutoTimeZone || loc.TimeZone == "" {
+		return
+	}
+	tz, err := time.LoadLocation(loc.TimeZone)
+	if err != nil {
+		s.logger.Warn("failed to load resolved time zone", "time_zone", loc.TimeZone, "err", err)
+		return
+	}
+	sys := s.System()
+	sys.Location.TimeLocation = tz
+	s.SetSystem(sys)
+}
+
+// System overrides devices.ControllerBase.System to serialize access to the
+// embedded system against the concurrent writes that SetSystem performs,
+// since Forecasts may be called from multiple goroutines (including the
+// background prefetch loop) while resolveLocation is updating it.
+func (s *Service) System() devices.System {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ControllerBase.System()
+}
+
+// SetSystem overrides devices.ControllerBase.SetSystem to serialize access
+// to the embedded system (see System above) and to start the background
+// prefetch goroutine, if configured, once the system location it needs is
+// available.
+func (s *Service) SetSystem(sys devices.System) {
+	s.mu.Lock()
+	s.ControllerBase.SetSystem(sys)
+	s.mu.Unlock()
+	if s.ControllerConfigCustom.PrefetchInterval <= 0 {
+		return
+	}
+	s.prefetchOnce.Do(func() {
+		go s.prefetchLoop()
+	})
+}
+
+// prefetchLoop calls Forecasts for the system location at every tick of
+// PrefetchInterval. Entries still valid in the backend's cache are served
+// from it rather than refreshed, so an entry is only re-fetched on the
+// first tick after it has expired. Errors are logged rather than returned
+// since there is no caller to return them to.
+func (s *Service) prefetchLoop() {
+	ticker := time.NewTicker(s.ControllerConfigCustom.PrefetchInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if _, err := s.Forecasts(context.Background(), devices.OperationArgs{}); err != nil {
+			s.logger.Warn("prefetch failed", "err", err)
+		}
+	}
+}
+
 func (s *Service) Implementation() any {
 	return s
 }
 
-type ForecastConfig struct{}
+type ForecastConfig struct {
+	// Hourly causes conditions evaluated against this device to prefer
+	// hour granular forecast periods over the standard 12-hour periods,
+	// when the configured backend has been asked (via its backend_options:
+	// hourly: true) to fetch them. This avoids, for example, a 12-hour
+	// "Partly Cloudy" period masking a sunny morning.
+	Hourly bool `yaml:"hourly"`
+}
 
 type Forecast struct {
 	devices.DeviceBase[ForecastConfig]
@@ -146,25 +314,37 @@ func (f *Forecast) ControlledBy() devices.Controller {
 
 func (f *Forecast) Conditions() map[string]devices.Condition {
 	return map[string]devices.Condition{
-		"cloud-cover":     f.Opacity,
-		"max-cloud-cover": f.MaxOpacity,
-		"min-cloud-cover": f.MinOpacity,
-		"mostly-sunny":    f.MostlySunny,
-		"partly-cloudy":   f.PartlyCloudy,
-		"partly-sunny":    f.PartlyCloudy,
-		"mostly-cloudy":   f.MostlyCloudy,
+		"cloud-cover":         f.Opacity,
+		"max-cloud-cover":     f.MaxOpacity,
+		"min-cloud-cover":     f.MinOpacity,
+		"mostly-sunny":        f.MostlySunny,
+		"partly-cloudy":       f.PartlyCloudy,
+		"partly-sunny":        f.PartlyCloudy,
+		"mostly-cloudy":       f.MostlyCloudy,
+		"temperature-above":   f.TemperatureAbove,
+		"temperature-below":   f.TemperatureBelow,
+		"wind-above":          f.WindAbove,
+		"precip-chance-above": f.PrecipChanceAbove,
+		"humidity-above":      f.HumidityAbove,
+		"is-daytime":          f.IsDaytime,
 	}
 }
 
 func (f *Forecast) ConditionsHelp() map[string]string {
 	return map[string]string{
-		"cloud-cover":     "returns the cloud coverage at the current time",
-		"max-cloud-cover": fmt.Sprintf("returns true if the cloud coverage is at most one of %v", argsValues),
-		"min-cloud-cover": fmt.Sprintf("returns true if the cloud coverage is at least one of %v", argsValues),
-		"mostly-sunny":    "returns true if the cloud coverage is at most mostly sunny",
-		"partly-sunny":    "returns true if the cloud coverage is exactly partly sunny/cloudy",
-		"partly-cloudy":   "returns true if the cloud coverage is exactly partly sunny/cloudy",
-		"mostly-cloudy":   "returns true if the cloud coverage is at least mostly cloudy",
+		"cloud-cover":         fmt.Sprintf("returns true if the cloud coverage is exactly one of %v", argsValues),
+		"max-cloud-cover":     fmt.Sprintf("returns true if the cloud coverage is at most one of %v", argsValues),
+		"min-cloud-cover":     fmt.Sprintf("returns true if the cloud coverage is at least one of %v", argsValues),
+		"mostly-sunny":        "returns true if the cloud coverage is at most mostly sunny",
+		"partly-sunny":        "returns true if the cloud coverage is exactly partly sunny/cloudy",
+		"partly-cloudy":       "returns true if the cloud coverage is exactly partly sunny/cloudy",
+		"mostly-cloudy":       "returns true if the cloud coverage is at least mostly cloudy",
+		"temperature-above":   "returns true if the forecast temperature is above the supplied value",
+		"temperature-below":   "returns true if the forecast temperature is below the supplied value",
+		"wind-above":          "returns true if the forecast wind speed is above the supplied value",
+		"precip-chance-above": "returns true if the forecast probability of precipitation is above the supplied percentage",
+		"humidity-above":      "returns true if the forecast relative humidity is above the supplied percentage",
+		"is-daytime":          "returns true if the forecast period is a daytime period",
 	}
 }
 
@@ -184,34 +364,72 @@ func init() {
 	argsValues = strings.Join(strs, ", ")
 }
 
-func (f *Forecast) opacity(ctx context.Context, opts devices.OperationArgs) (forecast, wanted nws.OpaqueCloudCoverage, err error) {
+// periodAt returns the forecast period that covers opts.Due, defaulting Due
+// to the current time at the system's location when it is unset, and
+// preferring hour granular periods over the standard 12-hour periods when
+// this device has been configured to do so.
+func (f *Forecast) periodAt(ctx context.Context, opts devices.OperationArgs) (backend.Period, error) {
+	fc, err := f.service.Forecasts(ctx, opts)
+	if err != nil {
+		return backend.Period{}, err
+	}
+	if opts.Due.Equal(time.Time{}) {
+		opts.Due = time.Now().In(f.service.System().Location.TimeLocation)
+	}
+	var p backend.Period
+	var ok bool
+	if f.DeviceConfigCustom.Hourly {
+		p, ok = fc.PeriodFor(opts.Due)
+	} else {
+		p, ok = fc.ForTime(opts.Due)
+	}
+	if !ok {
+		return backend.Period{}, fmt.Errorf("no forecast available for time: %v", opts.Due)
+	}
+	return p, nil
+}
+
+func (f *Forecast) opacity(ctx context.Context, opts devices.OperationArgs) (forecast, wanted backend.OpaqueCloudCoverage, err error) {
 	if len(opts.Args) != 1 {
 		err = fmt.Errorf("expected an argument for cloud cover: one of %v", argsValues)
 		return
 	}
-	wanted = nws.CloudOpacityFromShortForecast(opts.Args[0])
-	if wanted == nws.UnknownOpaqueCloudCoverage {
+	wanted = backend.CloudOpacityFromShortForecast(opts.Args[0])
+	if wanted == backend.UnknownOpaqueCloudCoverage {
 		err = fmt.Errorf("unknown cloud cover: %q not one of %v", opts.Args[0], argsValues)
 		return
 	}
-	fc, err := f.service.Forecasts(ctx, opts)
+	p, err := f.periodAt(ctx, opts)
 	if err != nil {
 		return
 	}
-	if opts.Due.Equal(time.Time{}) {
-		opts.Due = time.Now().In(f.service.System().Location.TimeLocation)
+	forecast = p.OpaqueCloudCoverage
+	return forecast, wanted, nil
+}
+
+// scalarArg parses the single float64 argument expected by the
+// temperature/wind/precipitation/humidity conditions.
+func (f *Forecast) scalarArg(name string, opts devices.OperationArgs) (float64, error) {
+	if len(opts.Args) != 1 {
+		return 0, fmt.Errorf("expected a single numeric argument for %s", name)
 	}
-	p, ok := fc.PeriodFor(opts.Due)
-	if !ok {
-		err = fmt.Errorf("no forecast available for time: %v", opts.Due)
-		return
+	v, err := strconv.ParseFloat(opts.Args[0], 64)
+	if err != nil {
+		return 0, fmt.Errorf("expected a single numeric argument for %s: %w", name, err)
 	}
-	forecast = nws.CloudOpacityFromShortForecast(p.ShortForecast)
-	if forecast == nws.UnknownOpaqueCloudCoverage {
-		err = fmt.Errorf("unknown cloud cover in forecast: %q", p.ShortForecast)
-		return
+	return v, nil
+}
+
+// windSpeedValue extracts the leading numeric value from a wind speed
+// string, eg. "5 mph" or "10 to 15 mph", since NWS reports wind speed as a
+// free form string rather than a quantity.
+func windSpeedValue(windSpeed string) float64 {
+	fields := strings.Fields(windSpeed)
+	if len(fields) == 0 {
+		return 0
 	}
-	return forecast, wanted, nil
+	v, _ := strconv.ParseFloat(fields[0], 64)
+	return v
 }
 
 func (f *Forecast) writeMsg(wr io.Writer, msg string) {
@@ -221,46 +439,133 @@ func (f *Forecast) writeMsg(wr io.Writer, msg string) {
 }
 
 // Opacity returns true if the cloud coverage is exactly that specified by the argument.
-func (f *Forecast) Opacity(ctx context.Context, opts devices.OperationArgs) (bool, error) {
+func (f *Forecast) Opacity(ctx context.Context, opts devices.OperationArgs) (any, bool, error) {
 	fc, arg, err := f.opacity(ctx, opts)
 	if err != nil {
-		return false, err
+		return nil, false, err
 	}
 	f.writeMsg(opts.Writer, fmt.Sprintf("Opacity: forecast: %v, wanted: %v == %v\n", fc, fc, arg))
-	return fc == arg, nil
+	return fc, fc == arg, nil
 }
 
 // MaxOpacity returns true if the cloud coverage is at most that specified by the argument.
-func (f *Forecast) MaxOpacity(ctx context.Context, opts devices.OperationArgs) (bool, error) {
+func (f *Forecast) MaxOpacity(ctx context.Context, opts devices.OperationArgs) (any, bool, error) {
 	fc, arg, err := f.opacity(ctx, opts)
 	if err != nil {
-		return false, err
+		return nil, false, err
 	}
 	f.writeMsg(opts.Writer, fmt.Sprintf("MaxOpacity: forecast: %v, wanted: %v <= %v\n", fc, fc, arg))
-	return fc <= arg, nil
+	return fc, fc <= arg, nil
 }
 
 // MinOpacity returns true if the cloud coverage is at most that specified by the argument.
-func (f *Forecast) MinOpacity(ctx context.Context, opts devices.OperationArgs) (bool, error) {
+func (f *Forecast) MinOpacity(ctx context.Context, opts devices.OperationArgs) (any, bool, error) {
 	fc, arg, err := f.opacity(ctx, opts)
 	if err != nil {
-		return false, err
+		return nil, false, err
 	}
 	f.writeMsg(opts.Writer, fmt.Sprintf("MinOpacity: forecast: %v, wanted: %v >= %v\n", fc, fc, arg))
-	return fc >= arg, nil
+	return fc, fc >= arg, nil
 }
 
-func (f *Forecast) MostlySunny(ctx context.Context, opts devices.OperationArgs) (bool, error) {
+func (f *Forecast) MostlySunny(ctx context.Context, opts devices.OperationArgs) (any, bool, error) {
 	opts.Args = []string{"Mostly Sunny"}
 	return f.MaxOpacity(ctx, opts)
 }
 
-func (f *Forecast) PartlyCloudy(ctx context.Context, opts devices.OperationArgs) (bool, error) {
+func (f *Forecast) PartlyCloudy(ctx context.Context, opts devices.OperationArgs) (any, bool, error) {
 	opts.Args = []string{"Partly Sunny"}
 	return f.Opacity(ctx, opts)
 }
 
-func (f *Forecast) MostlyCloudy(ctx context.Context, opts devices.OperationArgs) (bool, error) {
+func (f *Forecast) MostlyCloudy(ctx context.Context, opts devices.OperationArgs) (any, bool, error) {
 	opts.Args = []string{"Mostly Cloudy"}
 	return f.MinOpacity(ctx, opts)
 }
+
+// TemperatureAbove returns true if the forecast temperature is above the
+// value supplied as the condition's argument.
+func (f *Forecast) TemperatureAbove(ctx context.Context, opts devices.OperationArgs) (any, bool, error) {
+	want, err := f.scalarArg("temperature-above", opts)
+	if err != nil {
+		return nil, false, err
+	}
+	p, err := f.periodAt(ctx, opts)
+	if err != nil {
+		return nil, false, err
+	}
+	f.writeMsg(opts.Writer, fmt.Sprintf("TemperatureAbove: forecast: %v, wanted: > %v\n", p.Temperature, want))
+	return p.Temperature, p.Temperature > want, nil
+}
+
+// TemperatureBelow returns true if the forecast temperature is below the
+// value supplied as the condition's argument.
+func (f *Forecast) TemperatureBelow(ctx context.Context, opts devices.OperationArgs) (any, bool, error) {
+	want, err := f.scalarArg("temperature-below", opts)
+	if err != nil {
+		return nil, false, err
+	}
+	p, err := f.periodAt(ctx, opts)
+	if err != nil {
+		return nil, false, err
+	}
+	f.writeMsg(opts.Writer, fmt.Sprintf("TemperatureBelow: forecast: %v, wanted: < %v\n", p.Temperature, want))
+	return p.Temperature, p.Temperature < want, nil
+}
+
+// WindAbove returns true if the forecast wind speed is above the value
+// supplied as the condition's argument.
+func (f *Forecast) WindAbove(ctx context.Context, opts devices.OperationArgs) (any, bool, error) {
+	want, err := f.scalarArg("wind-above", opts)
+	if err != nil {
+		return nil, false, err
+	}
+	p, err := f.periodAt(ctx, opts)
+	if err != nil {
+		return nil, false, err
+	}
+	speed := windSpeedValue(p.WindSpeed)
+	f.writeMsg(opts.Writer, fmt.Sprintf("WindAbove: forecast: %v, wanted: > %v\n", speed, want))
+	return speed, speed > want, nil
+}
+
+// PrecipChanceAbove returns true if the forecast probability of
+// precipitation is above the percentage supplied as the condition's
+// argument.
+func (f *Forecast) PrecipChanceAbove(ctx context.Context, opts devices.OperationArgs) (any, bool, error) {
+	want, err := f.scalarArg("precip-chance-above", opts)
+	if err != nil {
+		return nil, false, err
+	}
+	p, err := f.periodAt(ctx, opts)
+	if err != nil {
+		return nil, false, err
+	}
+	f.writeMsg(opts.Writer, fmt.Sprintf("PrecipChanceAbove: forecast: %v, wanted: > %v\n", p.ProbabilityOfPrecipitation, want))
+	return p.ProbabilityOfPrecipitation, p.ProbabilityOfPrecipitation > want, nil
+}
+
+// HumidityAbove returns true if the forecast relative humidity is above the
+// percentage supplied as the condition's argument.
+func (f *Forecast) HumidityAbove(ctx context.Context, opts devices.OperationArgs) (any, bool, error) {
+	want, err := f.scalarArg("humidity-above", opts)
+	if err != nil {
+		return nil, false, err
+	}
+	p, err := f.periodAt(ctx, opts)
+	if err != nil {
+		return nil, false, err
+	}
+	f.writeMsg(opts.Writer, fmt.Sprintf("HumidityAbove: forecast: %v, wanted: > %v\n", p.RelativeHumidity, want))
+	return p.RelativeHumidity, p.RelativeHumidity > want, nil
+}
+
+// IsDaytime returns true if the forecast period is a daytime period.
+func (f *Forecast) IsDaytime(ctx context.Context, opts devices.OperationArgs) (any, bool, error) {
+	p, err := f.periodAt(ctx, opts)
+	if err != nil {
+		return nil, false, err
+	}
+	f.writeMsg(opts.Writer, fmt.Sprintf("IsDaytime: forecast: %v\n", p.IsDaytime))
+	return p.IsDaytime, p.IsDaytime, nil
+}
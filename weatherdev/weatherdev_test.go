@@ -6,18 +6,52 @@ package weatherdev_test
 
 import (
 	"context"
+	"io"
 	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"cloudeng.io/datetime"
-	"cloudeng.io/webapi/clients/nws"
-	"cloudeng.io/webapi/clients/nws/nwstestutil"
+	"cloudeng.io/webapi/webapitestutil"
 	"github.com/cosnicolaou/automation/devices"
+	"github.com/cosnicolaou/weather/backend"
 	"github.com/cosnicolaou/weather/weatherdev"
+	"github.com/cosnicolaou/weather/weathergov"
 )
 
+func writeFile(name string, w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	f, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+	io.Copy(w, f)
+}
+
+func runMock() *httptest.Server {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.Contains(r.URL.Path, "/points/"):
+			writeFile("gridpoint.json", w)
+		case strings.Contains(r.URL.Path, "/forecast/hourly"):
+			writeFile("forecast_hourly.json", w)
+		case strings.Contains(r.URL.Path, "forecast"):
+			writeFile("forecast.json", w)
+		case strings.Contains(r.URL.Path, "/alerts/active"):
+			writeFile("alerts.json", w)
+		}
+	})
+	return webapitestutil.NewServer(handler)
+}
+
 func invokeCondition(t *testing.T, dev *weatherdev.Forecast, cond string, when time.Time, arg string) bool {
 	ctx := context.Background()
 	_, cover, err := dev.Conditions()[cond](ctx, devices.OperationArgs{
@@ -33,12 +67,11 @@ func invokeCondition(t *testing.T, dev *weatherdev.Forecast, cond string, when t
 func TestMaxCloudCoverage(t *testing.T) {
 	ctx := context.Background()
 
-	srv := nwstestutil.NewMockServer()
-	srv.SetValidTimes(time.Now())
+	srv := runMock()
 	defer srv.Close()
-	url := srv.Run()
-	api := nws.NewAPI()
-	api.SetHost(url)
+
+	api := weathergov.NewAPI()
+	api.SetHost(srv.URL)
 
 	sys := devices.System{
 		Location: devices.Location{
@@ -56,7 +89,7 @@ func TestMaxCloudCoverage(t *testing.T) {
 		Logger: logger,
 	})
 	ws.SetSystem(sys)
-	ws.SetNWSAPI(api)
+	ws.SetBackend(api)
 
 	forecast, err := ws.Forecasts(ctx, devices.OperationArgs{})
 	if err != nil {
@@ -77,8 +110,8 @@ func TestMaxCloudCoverage(t *testing.T) {
 			exactCover := invokeCondition(t, dev, "cloud-cover", when, arg)
 
 			var maxOpacity, minOpacity bool
-			forecastOpacity := nws.CloudOpacityFromShortForecast(fc.ShortForecast)
-			argOpacity := nws.CloudOpacityFromShortForecast(arg)
+			forecastOpacity := backend.CloudOpacityFromShortForecast(fc.ShortForecast)
+			argOpacity := backend.CloudOpacityFromShortForecast(arg)
 			if forecastOpacity <= argOpacity {
 				maxOpacity = true
 			}
@@ -105,20 +138,285 @@ func TestMaxCloudCoverage(t *testing.T) {
 				t.Errorf("period: %v, forecast: %q: arg: %q got %v, want %v", i, fc.ShortForecast, arg, got, want)
 			}
 
-			opc := nws.CloudOpacityFromShortForecast(fc.ShortForecast)
-			if opc == nws.UnknownOpaqueCloudCoverage {
+			opc := backend.CloudOpacityFromShortForecast(fc.ShortForecast)
+			if opc == backend.UnknownOpaqueCloudCoverage {
 				t.Fatalf("unknown cloud cover: %q", arg)
 			}
-			if got, want := mostlySunny, opc <= nws.MostlyClearSunny; got != want {
+			if got, want := mostlySunny, opc <= backend.MostlyClearSunny; got != want {
 				t.Errorf("period: %v, forecast: %q: mostlySunny got %v, want %v", i, fc.ShortForecast, got, want)
 			}
-			if got, want := mostlyCloudy, opc >= nws.MostlyCloudy; got != want {
+			if got, want := mostlyCloudy, opc >= backend.MostlyCloudy; got != want {
 				t.Errorf("period: %v, forecast: %q: mostlyCloudy got %v, want %v", i, fc.ShortForecast, got, want)
 			}
 		}
 	}
+}
+
+func TestScalarConditions(t *testing.T) {
+	ctx := context.Background()
+
+	srv := runMock()
+	defer srv.Close()
+
+	api := weathergov.NewAPI()
+	api.SetHost(srv.URL)
+
+	sys := devices.System{
+		Location: devices.Location{
+			Place: datetime.Place{
+				TimeLocation: time.UTC,
+				Latitude:     37.7749,
+				Longitude:    -122.4194,
+			},
+		},
+	}
+
+	logOut := &strings.Builder{}
+	logger := slog.New(slog.NewJSONHandler(logOut, nil))
+	ws := weatherdev.NewService(devices.Options{
+		Logger: logger,
+	})
+	ws.SetSystem(sys)
+	ws.SetBackend(api)
+
+	forecast, err := ws.Forecasts(ctx, devices.OperationArgs{})
+	if err != nil {
+		t.Fatalf("failed to get forecasts: %v", err)
+	}
+
+	dev := weatherdev.NewForecast(devices.Options{Logger: logger})
+	dev.SetController(ws)
+
+	// The first period has a temperature of 14F, wind speed of "5 mph",
+	// no chance of precipitation and is a daytime period.
+	first := forecast.Periods[0]
+	when := first.StartTime
+
+	if got, want := invokeCondition(t, dev, "temperature-above", when, "10"), true; got != want {
+		t.Errorf("temperature-above(10): got %v, want %v", got, want)
+	}
+	if got, want := invokeCondition(t, dev, "temperature-above", when, "20"), false; got != want {
+		t.Errorf("temperature-above(20): got %v, want %v", got, want)
+	}
+	if got, want := invokeCondition(t, dev, "temperature-below", when, "20"), true; got != want {
+		t.Errorf("temperature-below(20): got %v, want %v", got, want)
+	}
+	if got, want := invokeCondition(t, dev, "temperature-below", when, "10"), false; got != want {
+		t.Errorf("temperature-below(10): got %v, want %v", got, want)
+	}
+
+	// windSpeedValue must extract the leading number from the free-form
+	// NWS wind speed strings, including ranges like "5 to 10 mph".
+	if got, want := invokeCondition(t, dev, "wind-above", when, "3"), true; got != want {
+		t.Errorf("wind-above(3): got %v, want %v", got, want)
+	}
+	if got, want := invokeCondition(t, dev, "wind-above", when, "10"), false; got != want {
+		t.Errorf("wind-above(10): got %v, want %v", got, want)
+	}
+	rangedWind := forecast.Periods[2].StartTime // windSpeed: "5 to 10 mph"
+	if got, want := invokeCondition(t, dev, "wind-above", rangedWind, "3"), true; got != want {
+		t.Errorf("wind-above(3) for ranged wind speed: got %v, want %v", got, want)
+	}
+
+	if got, want := invokeCondition(t, dev, "precip-chance-above", when, "10"), false; got != want {
+		t.Errorf("precip-chance-above(10): got %v, want %v", got, want)
+	}
+	rainy := forecast.Periods[7].StartTime // probabilityOfPrecipitation: 20
+	if got, want := invokeCondition(t, dev, "precip-chance-above", rainy, "10"), true; got != want {
+		t.Errorf("precip-chance-above(10) for rainy period: got %v, want %v", got, want)
+	}
+
+	if got, want := invokeCondition(t, dev, "humidity-above", when, "50"), true; got != want {
+		t.Errorf("humidity-above(50): got %v, want %v", got, want)
+	}
+	if got, want := invokeCondition(t, dev, "humidity-above", when, "90"), false; got != want {
+		t.Errorf("humidity-above(90): got %v, want %v", got, want)
+	}
 
-	if got, want := srv.ForecastCalls(), 1; got != want {
-		t.Errorf("got %v, want %v", got, want)
+	if got, want := invokeCondition(t, dev, "is-daytime", when, ""), true; got != want {
+		t.Errorf("is-daytime: got %v, want %v", got, want)
+	}
+	night := forecast.Periods[1].StartTime
+	if got, want := invokeCondition(t, dev, "is-daytime", night, ""), false; got != want {
+		t.Errorf("is-daytime at night: got %v, want %v", got, want)
+	}
+}
+
+// TestHourlyDeviceConfig exercises the device-level "hourly" config option:
+// the 12-hour period covering the first hourly bucket's start time is
+// "Partly Sunny", but weatherdev/testdata/forecast_hourly.json reports that
+// same hour as "Sunny" -- a device configured with Hourly: true should
+// prefer the finer-grained hourly bucket over the coarser 12-hour one.
+func TestHourlyDeviceConfig(t *testing.T) {
+	ctx := context.Background()
+
+	srv := runMock()
+	defer srv.Close()
+
+	api := weathergov.NewAPI()
+	api.SetHost(srv.URL)
+	if err := api.Configure(map[string]any{"hourly": true}); err != nil {
+		t.Fatalf("failed to configure backend: %v", err)
+	}
+
+	sys := devices.System{
+		Location: devices.Location{
+			Place: datetime.Place{
+				TimeLocation: time.UTC,
+				Latitude:     37.7749,
+				Longitude:    -122.4194,
+			},
+		},
+	}
+
+	logOut := &strings.Builder{}
+	logger := slog.New(slog.NewJSONHandler(logOut, nil))
+	ws := weatherdev.NewService(devices.Options{Logger: logger})
+	ws.SetSystem(sys)
+	ws.SetBackend(api)
+
+	forecast, err := ws.Forecasts(ctx, devices.OperationArgs{})
+	if err != nil {
+		t.Fatalf("failed to get forecasts: %v", err)
+	}
+	if len(forecast.HourlyPeriods) == 0 {
+		t.Fatalf("expected hourly periods to be populated")
+	}
+	when := forecast.HourlyPeriods[0].StartTime
+
+	dev := weatherdev.NewForecast(devices.Options{Logger: logger})
+	dev.DeviceConfigCustom.Hourly = true
+	dev.SetController(ws)
+
+	if got, want := invokeCondition(t, dev, "cloud-cover", when, "Sunny"), true; got != want {
+		t.Errorf("cloud-cover(Sunny): got %v, want %v", got, want)
+	}
+	if got, want := invokeCondition(t, dev, "cloud-cover", when, "Partly Sunny"), false; got != want {
+		t.Errorf("cloud-cover(Partly Sunny): got %v, want %v", got, want)
+	}
+
+	// The same timestamp against the coarser 12-hour periods should report
+	// the masking "Partly Sunny" period instead.
+	coarse := weatherdev.NewForecast(devices.Options{Logger: logger})
+	coarse.SetController(ws)
+	if got, want := invokeCondition(t, coarse, "cloud-cover", when, "Partly Sunny"), true; got != want {
+		t.Errorf("12-hour cloud-cover(Partly Sunny): got %v, want %v", got, want)
+	}
+}
+
+func invokeAlertCondition(t *testing.T, dev *weatherdev.Alerts, cond string, arg string) (any, bool) {
+	ctx := context.Background()
+	var args devices.OperationArgs
+	if arg != "" {
+		args.Args = []string{arg}
+	}
+	v, ok, err := dev.Conditions()[cond](ctx, args)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	return v, ok
+}
+
+func TestAlertConditions(t *testing.T) {
+	srv := runMock()
+	defer srv.Close()
+
+	api := weathergov.NewAPI()
+	api.SetHost(srv.URL)
+
+	sys := devices.System{
+		Location: devices.Location{
+			Place: datetime.Place{
+				TimeLocation: time.UTC,
+				Latitude:     37.7749,
+				Longitude:    -122.4194,
+			},
+		},
+	}
+
+	logOut := &strings.Builder{}
+	logger := slog.New(slog.NewJSONHandler(logOut, nil))
+	ws := weatherdev.NewService(devices.Options{Logger: logger})
+	ws.SetSystem(sys)
+	ws.SetBackend(api)
+
+	alerts := weathergov.NewAlerts()
+	alerts.SetHost(srv.URL)
+	ws.SetAlerts(alerts)
+
+	dev := weatherdev.NewAlerts(devices.Options{Logger: logger})
+	dev.SetController(ws)
+
+	// weatherdev/testdata/alerts.json carries an Extreme "Tornado Warning"
+	// and a Minor "Winter Weather Advisory", both active.
+	if _, got := invokeAlertCondition(t, dev, "alert-active", ""); got != true {
+		t.Errorf("alert-active: got %v, want %v", got, true)
+	}
+
+	if _, got := invokeAlertCondition(t, dev, "alert-severity-at-least", "Severe"); got != true {
+		t.Errorf("alert-severity-at-least(Severe): got %v, want %v", got, true)
+	}
+	if _, got := invokeAlertCondition(t, dev, "alert-severity-at-least", "Extreme"); got != true {
+		t.Errorf("alert-severity-at-least(Extreme): got %v, want %v", got, true)
+	}
+
+	if _, got := invokeAlertCondition(t, dev, "alert-event-matches", "Tornado Warning"); got != true {
+		t.Errorf("alert-event-matches(Tornado Warning): got %v, want %v", got, true)
+	}
+	if _, got := invokeAlertCondition(t, dev, "alert-event-matches", "Flood Warning"); got != false {
+		t.Errorf("alert-event-matches(Flood Warning): got %v, want %v", got, false)
+	}
+}
+
+// TestConcurrentForecastsAndSetSystem exercises Forecasts, which
+// auto-fills the system's TimeLocation when AutoTimeZone is set, running
+// concurrently with SetSystem, which the background prefetch loop's
+// goroutine and the automation framework may both call. It is intended to
+// be run with -race.
+func TestConcurrentForecastsAndSetSystem(t *testing.T) {
+	ctx := context.Background()
+
+	srv := runMock()
+	defer srv.Close()
+
+	api := weathergov.NewAPI()
+	api.SetHost(srv.URL)
+
+	logOut := &strings.Builder{}
+	logger := slog.New(slog.NewJSONHandler(logOut, nil))
+	ws := weatherdev.NewService(devices.Options{
+		Logger: logger,
+	})
+	ws.ControllerConfigCustom.AutoTimeZone = true
+	ws.SetBackend(api)
+
+	sys := devices.System{
+		Location: devices.Location{
+			Place: datetime.Place{
+				TimeLocation: time.UTC,
+				Latitude:     37.7749,
+				Longitude:    -122.4194,
+			},
+		},
+	}
+	ws.SetSystem(sys)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := ws.Forecasts(ctx, devices.OperationArgs{}); err != nil {
+				t.Errorf("failed to get forecasts: %v", err)
+			}
+		}()
+	}
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ws.SetSystem(sys)
+		}()
 	}
+	wg.Wait()
 }
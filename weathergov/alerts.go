@@ -0,0 +1,230 @@
+// Copyright 2026 Cosmos Nicolaou. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package weathergov
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"cloudeng.io/webapi/operations"
+)
+
+// Severity represents the NWS alert severity scale, ordered from least to
+// most severe, as documented at
+// https://www.weather.gov/lwx/WarningsDefinitions.
+type Severity int
+
+const (
+	UnknownSeverity Severity = iota
+	MinorSeverity
+	ModerateSeverity
+	SevereSeverity
+	ExtremeSeverity
+)
+
+// SeverityFromString returns the Severity that matches the supplied NWS
+// severity string, eg. "Extreme", "Severe", "Moderate" or "Minor". It
+// returns UnknownSeverity if the string does not match any known severity.
+func SeverityFromString(severity string) Severity {
+	switch strings.ToLower(severity) {
+	case "minor":
+		return MinorSeverity
+	case "moderate":
+		return ModerateSeverity
+	case "severe":
+		return SevereSeverity
+	case "extreme":
+		return ExtremeSeverity
+	}
+	return UnknownSeverity
+}
+
+// AlertEventKind identifies how an Alert delivered by WatchAlerts relates
+// to previously observed alerts for the same location.
+type AlertEventKind string
+
+const (
+	// AlertNew is reported the first time an alert's id is observed.
+	AlertNew AlertEventKind = "new"
+	// AlertUpdated is reported when a previously observed alert's headline,
+	// severity or expiry changes.
+	AlertUpdated AlertEventKind = "updated"
+	// AlertCanceled is reported when a previously observed alert is no
+	// longer present in the active alerts for the location.
+	AlertCanceled AlertEventKind = "canceled"
+)
+
+// Alert represents a single NWS alert, normalized from the GeoJSON
+// properties returned by the /alerts/active endpoint.
+type Alert struct {
+	ID          string
+	Event       string
+	Headline    string
+	Description string
+	AreaDesc    string
+	Severity    Severity
+	Urgency     string
+	Certainty   string
+	Effective   time.Time
+	Expires     time.Time
+
+	// Kind is populated by WatchAlerts to indicate whether this Alert is
+	// new, updated or canceled relative to the previous poll. It is left
+	// at its zero value by GetActiveAlerts, which only ever returns a
+	// snapshot of the currently active alerts.
+	Kind AlertEventKind
+}
+
+type rawAlertProperties struct {
+	ID          string    `json:"id"`
+	AreaDesc    string    `json:"areaDesc"`
+	Event       string    `json:"event"`
+	Headline    string    `json:"headline"`
+	Description string    `json:"description"`
+	Severity    string    `json:"severity"`
+	Urgency     string    `json:"urgency"`
+	Certainty   string    `json:"certainty"`
+	Effective   time.Time `json:"effective"`
+	Expires     time.Time `json:"expires"`
+}
+
+func (p rawAlertProperties) toAlert() Alert {
+	return Alert{
+		ID:          p.ID,
+		Event:       p.Event,
+		Headline:    p.Headline,
+		Description: p.Description,
+		AreaDesc:    p.AreaDesc,
+		Severity:    SeverityFromString(p.Severity),
+		Urgency:     p.Urgency,
+		Certainty:   p.Certainty,
+		Effective:   p.Effective,
+		Expires:     p.Expires,
+	}
+}
+
+type rawAlertFeature struct {
+	Properties rawAlertProperties `json:"properties"`
+}
+
+type alertsResponse struct {
+	Features []rawAlertFeature `json:"features"`
+}
+
+// defaultAlertsPollInterval is used by WatchAlerts when PollInterval has not
+// been set.
+const defaultAlertsPollInterval = 5 * time.Minute
+
+// Alerts is a client for the National Weather Service's active alerts
+// endpoint. Unlike API, it is not a backend.Backend implementation since
+// alerts are NWS specific rather than a provider-agnostic forecast concept.
+type Alerts struct {
+	ep           *operations.Endpoint[alertsResponse]
+	opts         []operations.Option
+	host         string
+	PollInterval time.Duration
+}
+
+// NewAlerts creates a new, unconfigured, weather.gov alerts client.
+func NewAlerts(opts ...operations.Option) *Alerts {
+	return &Alerts{
+		opts: opts,
+		host: APIHost,
+		ep:   operations.NewEndpoint[alertsResponse](opts...),
+	}
+}
+
+// SetHost overrides the default api.weather.gov host, primarily for
+// testing.
+func (a *Alerts) SetHost(host string) {
+	a.host = host
+}
+
+// GetActiveAlerts returns the alerts currently active for the specified
+// lat/long.
+func (a *Alerts) GetActiveAlerts(ctx context.Context, lat, long float64) ([]Alert, error) {
+	u := fmt.Sprintf("%s/alerts/active?point=%f,%f", a.host, lat, long)
+	ar, _, _, err := a.ep.Get(ctx, u)
+	if err != nil {
+		return nil, fmt.Errorf("%v: alerts lookup failed: %w", u, err)
+	}
+	alerts := make([]Alert, len(ar.Features))
+	for i, f := range ar.Features {
+		alerts[i] = f.Properties.toAlert()
+	}
+	return alerts, nil
+}
+
+// WatchAlerts polls GetActiveAlerts at PollInterval (defaulting to 5
+// minutes) and returns a channel on which new, updated and canceled alerts
+// are delivered, identified by their Kind field. Alerts are deduplicated,
+// and their changes detected, by their id. The channel is closed once ctx
+// is done.
+func (a *Alerts) WatchAlerts(ctx context.Context, lat, long float64) <-chan Alert {
+	ch := make(chan Alert)
+	go func() {
+		defer close(ch)
+		interval := a.PollInterval
+		if interval <= 0 {
+			interval = defaultAlertsPollInterval
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		seen := map[string]Alert{}
+		poll := func() bool {
+			alerts, err := a.GetActiveAlerts(ctx, lat, long)
+			if err != nil {
+				return true
+			}
+			current := make(map[string]Alert, len(alerts))
+			for _, al := range alerts {
+				current[al.ID] = al
+				prev, ok := seen[al.ID]
+				switch {
+				case !ok:
+					al.Kind = AlertNew
+				case prev.Headline != al.Headline || prev.Severity != al.Severity || !prev.Expires.Equal(al.Expires):
+					al.Kind = AlertUpdated
+				default:
+					continue
+				}
+				select {
+				case ch <- al:
+				case <-ctx.Done():
+					return false
+				}
+			}
+			for id, prev := range seen {
+				if _, ok := current[id]; ok {
+					continue
+				}
+				prev.Kind = AlertCanceled
+				select {
+				case ch <- prev:
+				case <-ctx.Done():
+					return false
+				}
+			}
+			seen = current
+			return true
+		}
+		if !poll() {
+			return
+		}
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if !poll() {
+					return
+				}
+			}
+		}
+	}()
+	return ch
+}
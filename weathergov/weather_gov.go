@@ -2,155 +2,424 @@
 // Use of this source code is governed by the Apache-2.0
 // license that can be found in the LICENSE file.
 
+// Package weathergov provides a client for the National Weather Service API
+// that implements the backend.Backend interface.
 package weathergov
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
 	"net/url"
-	"os"
+	"strconv"
 	"strings"
 	"time"
 
 	"cloudeng.io/webapi/operations"
+	"github.com/cosnicolaou/weather/backend"
+	"github.com/cosnicolaou/weather/cache"
 )
 
+// Name is the name that this backend is registered under.
+const Name = "weather.gov"
+
+func init() {
+	backend.RegisterBackend(Name, func() backend.Backend { return NewAPI() })
+}
+
 // OpaqueCloudCoverage represents the cloud coverage as a fraction of the sky
 // as defined at https://www.weather.gov/bgm/forecast_terms.
-type OpaqueCloudCoverage int
+type OpaqueCloudCoverage = backend.OpaqueCloudCoverage
 
 const (
-	UnknownOpaqueCloudCoverage OpaqueCloudCoverage = iota
-	ClearSunny                                     // 0 to 1/8 Opaque Cloud Coverage
-	MostlyClearSunny                               // 1/8 to 3/8
-	PartlyCloudySunny                              // 3/8 to 5/8
-	MostlyCloudy                                   // 5/8 to 7/8
-	Cloudy
-	Rain
-	Snow
+	UnknownOpaqueCloudCoverage = backend.UnknownOpaqueCloudCoverage
+	ClearSunny                 = backend.ClearSunny
+	MostlyClearSunny           = backend.MostlyClearSunny
+	PartlyCloudySunny          = backend.PartlyCloudySunny
+	MostlyCloudy               = backend.MostlyCloudy
+	Cloudy                     = backend.Cloudy
+	Rain                       = backend.Rain
+	Snow                       = backend.Snow
 )
 
 const (
 	APIHost = "https://api.weather.gov"
 )
 
+type rawRelativeLocation struct {
+	Properties struct {
+		City  string `json:"city"`
+		State string `json:"state"`
+	} `json:"properties"`
+}
+
 type gridPointForecasts struct {
-	X        int    `json:"gridX"`
-	Y        int    `json:"gridY"`
-	Hourly   string `json:"forecastHourly"`
-	Forecast string `json:"forecast"`
+	ID               string              `json:"gridId"`
+	X                int                 `json:"gridX"`
+	Y                int                 `json:"gridY"`
+	Hourly           string              `json:"forecastHourly"`
+	RelativeLocation rawRelativeLocation `json:"relativeLocation"`
+	ForecastZone     string              `json:"forecastZone"`
+	County           string              `json:"county"`
+	TimeZone         string              `json:"timeZone"`
 }
 
 type gridPointResponse struct {
 	Properties gridPointForecasts `json:"properties"`
 }
 
-type Forecast struct {
-	StartTime           time.Time `json:"startTime"`
-	EndTime             time.Time `json:"endTime"`
-	Name                string    `json:"name"`
-	ShortForecast       string    `json:"shortForecast"`
-	OpaqueCloudCoverage OpaqueCloudCoverage
+// lastPathSegment returns the final "/" separated segment of a URL, which
+// for the forecastZone and county URLs returned by the NWS API is the zone
+// identifier, eg. "KSZ009" from
+// "https://api.weather.gov/zones/forecast/KSZ009".
+func lastPathSegment(u string) string {
+	if idx := strings.LastIndex(u, "/"); idx >= 0 {
+		return u[idx+1:]
+	}
+	return u
+}
+
+func (gp gridPointForecasts) toLocation() backend.Location {
+	return backend.Location{
+		City:           gp.RelativeLocation.Properties.City,
+		State:          gp.RelativeLocation.Properties.State,
+		GridID:         gp.ID,
+		TimeZone:       gp.TimeZone,
+		ForecastZoneID: lastPathSegment(gp.ForecastZone),
+		CountyID:       lastPathSegment(gp.County),
+	}
+}
+
+// Forecast is an alias for backend.Period retained for backwards
+// compatibility with existing callers of this package.
+type Forecast = backend.Period
+
+// Forecasts is an alias for backend.Forecast retained for backwards
+// compatibility with existing callers of this package.
+type Forecasts = backend.Forecast
+
+// rawQuantity mirrors the NWS quantitative value representation used for
+// fields such as probabilityOfPrecipitation, dewpoint and relativeHumidity,
+// eg. {"unitCode": "wmoUnit:percent", "value": 20}.
+type rawQuantity struct {
+	Value float64 `json:"value"`
+}
+
+// rawPeriod mirrors a single period as returned by both the 12-hourly and
+// hourly NWS forecast endpoints.
+type rawPeriod struct {
+	StartTime                  time.Time   `json:"startTime"`
+	EndTime                    time.Time   `json:"endTime"`
+	Name                       string      `json:"name"`
+	IsDaytime                  bool        `json:"isDaytime"`
+	Temperature                float64     `json:"temperature"`
+	TemperatureUnit            string      `json:"temperatureUnit"`
+	TemperatureTrend           string      `json:"temperatureTrend"`
+	WindSpeed                  string      `json:"windSpeed"`
+	WindDirection              string      `json:"windDirection"`
+	ShortForecast              string      `json:"shortForecast"`
+	DetailedForecast           string      `json:"detailedForecast"`
+	ProbabilityOfPrecipitation rawQuantity `json:"probabilityOfPrecipitation"`
+	DewPoint                   rawQuantity `json:"dewpoint"`
+	RelativeHumidity           rawQuantity `json:"relativeHumidity"`
+}
+
+func (p rawPeriod) toPeriod() Forecast {
+	return Forecast{
+		StartTime:                  p.StartTime,
+		EndTime:                    p.EndTime,
+		Name:                       p.Name,
+		ShortForecast:              p.ShortForecast,
+		DetailedForecast:           p.DetailedForecast,
+		OpaqueCloudCoverage:        CloudOpacityFromShortForecast(p.ShortForecast),
+		IsDaytime:                  p.IsDaytime,
+		Temperature:                p.Temperature,
+		TemperatureUnit:            p.TemperatureUnit,
+		TemperatureTrend:           p.TemperatureTrend,
+		WindSpeed:                  p.WindSpeed,
+		WindDirection:              p.WindDirection,
+		ProbabilityOfPrecipitation: p.ProbabilityOfPrecipitation.Value,
+		DewPoint:                   p.DewPoint.Value,
+		RelativeHumidity:           p.RelativeHumidity.Value,
+	}
+}
+
+func periodsFrom(raw []rawPeriod) []Forecast {
+	periods := make([]Forecast, len(raw))
+	for i, p := range raw {
+		periods[i] = p.toPeriod()
+	}
+	return periods
 }
 
 type forecastResponse struct {
 	Properties struct {
-		Periods []Forecast `json:"periods"`
+		Periods []rawPeriod `json:"periods"`
 	}
 }
 
-type Forecasts struct {
-	Lat     float64
-	Long    float64
-	GridX   int
-	GridY   int
-	Periods []Forecast
-}
+const (
+	// defaultForecastMinTTL is the minimum time a forecast response is
+	// cached for, used when the NWS response does not specify a longer
+	// Expires/Cache-Control lifetime.
+	defaultForecastMinTTL = 5 * time.Minute
+	// defaultGridMinTTL is the minimum time a grid point lookup is cached
+	// for. Grid points rarely change so they are cached far longer than
+	// forecasts, mirroring the NWS API's own two-tier structure of a grid
+	// point lookup followed by a forecast lookup.
+	defaultGridMinTTL = 24 * time.Hour
+)
 
+// API is a weathergov client that implements the backend.Backend interface.
 type API struct {
-	gridEP *operations.Endpoint[gridPointResponse]
-	opts   []operations.Option
-	host   string
+	gridEP     *operations.Endpoint[gridPointResponse]
+	opts       []operations.Option
+	host       string
+	hourly     bool
+	cache      cache.Cache
+	minTTL     time.Duration
+	gridMinTTL time.Duration
 }
 
+// NewAPI creates a new, unconfigured, weather.gov API client. Responses are
+// cached in memory by default; use the "cache_dir" Configure option to
+// persist the cache to disk instead.
 func NewAPI(opts ...operations.Option) *API {
 	return &API{
-		opts:   opts,
-		host:   APIHost,
-		gridEP: operations.NewEndpoint[gridPointResponse](opts...),
+		opts:       opts,
+		host:       APIHost,
+		gridEP:     operations.NewEndpoint[gridPointResponse](opts...),
+		cache:      cache.NewMemory(),
+		minTTL:     defaultForecastMinTTL,
+		gridMinTTL: defaultGridMinTTL,
 	}
 }
+
 func (a *API) SetHost(host string) {
 	a.host = host
 }
 
-func (a *API) GetForecast(ctx context.Context, lat, long float64) (Forecasts, error) {
-	//var u url.URL
-	//	u.Scheme = "https"
-	//	u.Host = a.host
-	//	u.Path = fmt.Sprintf("%s/points/%f,%f", a.host, lat, long)
+// Name implements backend.Backend.
+func (a *API) Name() string {
+	return Name
+}
+
+// Configure implements backend.Backend. It supports a "host" option that
+// overrides the default api.weather.gov host, primarily for testing; an
+// "hourly" option that, when true, causes Fetch to also populate
+// Forecast.HourlyPeriods; a "cache_dir" option that switches the response
+// cache from its default in-memory store to one persisted under the given
+// directory; and "min_ttl"/"grid_min_ttl" duration string options that set
+// the minimum time forecast and grid point lookups, respectively, are
+// cached for, regardless of the Expires/Cache-Control headers returned by
+// the NWS API.
+func (a *API) Configure(options map[string]any) error {
+	if h, ok := options["host"]; ok {
+		host, ok := h.(string)
+		if !ok {
+			return fmt.Errorf("weather.gov: host option must be a string, got %T", h)
+		}
+		a.SetHost(host)
+	}
+	if h, ok := options["hourly"]; ok {
+		hourly, ok := h.(bool)
+		if !ok {
+			return fmt.Errorf("weather.gov: hourly option must be a bool, got %T", h)
+		}
+		a.hourly = hourly
+	}
+	if h, ok := options["cache_dir"]; ok {
+		dir, ok := h.(string)
+		if !ok {
+			return fmt.Errorf("weather.gov: cache_dir option must be a string, got %T", h)
+		}
+		a.cache = cache.NewFilesystem(dir)
+	}
+	if h, ok := options["min_ttl"]; ok {
+		ttl, err := parseTTLOption("min_ttl", h)
+		if err != nil {
+			return err
+		}
+		a.minTTL = ttl
+	}
+	if h, ok := options["grid_min_ttl"]; ok {
+		ttl, err := parseTTLOption("grid_min_ttl", h)
+		if err != nil {
+			return err
+		}
+		a.gridMinTTL = ttl
+	}
+	return nil
+}
+
+func parseTTLOption(name string, v any) (time.Duration, error) {
+	s, ok := v.(string)
+	if !ok {
+		return 0, fmt.Errorf("weather.gov: %s option must be a duration string, got %T", name, v)
+	}
+	ttl, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("weather.gov: %s option: %w", name, err)
+	}
+	return ttl, nil
+}
+
+// Fetch implements backend.Backend. The days argument is currently ignored
+// since the NWS forecast endpoint always returns its standard set of
+// periods. If this API was configured with the "hourly" option, the
+// returned Forecast also has HourlyPeriods populated.
+func (a *API) Fetch(ctx context.Context, lat, long float64, _ int) (backend.Forecast, error) {
+	fc, err := a.GetForecast(ctx, lat, long)
+	if err != nil {
+		return backend.Forecast{}, err
+	}
+	if !a.hourly {
+		return fc, nil
+	}
+	hourly, err := a.GetHourlyForecast(ctx, lat, long)
+	if err != nil {
+		return backend.Forecast{}, err
+	}
+	fc.HourlyPeriods = hourly.HourlyPeriods
+	return fc, nil
+}
+
+func (a *API) lookupGridPoint(ctx context.Context, lat, long float64) (gridPointForecasts, error) {
 	u, err := url.Parse(fmt.Sprintf("%s/points/%f,%f", a.host, lat, long))
 	if err != nil {
-		return Forecasts{}, fmt.Errorf("failed to parse URL: %w", err)
+		return gridPointForecasts{}, fmt.Errorf("failed to parse URL: %w", err)
 	}
-	gpr, buf, _, err := a.gridEP.Get(ctx, u.String())
+	key := cache.Key{Lat: lat, Long: long, Endpoint: "points"}
+	gpr, err := fetchWithCache(ctx, a.gridEP, a.cache, key, a.gridMinTTL, u.String())
 	if err != nil {
-		return Forecasts{}, fmt.Errorf("%v: grid point lookup failed: %w", u.String(), err)
+		return gridPointForecasts{}, fmt.Errorf("%v: grid point lookup failed: %w", u.String(), err)
 	}
-	os.WriteFile("gridpoint.json", buf, 0644)
-	fcep := operations.NewEndpoint[forecastResponse](a.opts...)
-	up, err := url.Parse(gpr.Properties.Forecast)
+	return gpr.Properties, nil
+}
+
+// fetchWithCache returns the cached response for key if present and not
+// expired, otherwise it issues a GET request against url, caching the
+// result for at least minTTL, or longer if the response's
+// Cache-Control/Expires headers request it.
+func fetchWithCache[T any](ctx context.Context, ep *operations.Endpoint[T], c cache.Cache, key cache.Key, minTTL time.Duration, u string) (T, error) {
+	var zero T
+	if c != nil {
+		if entry, ok := c.Get(key); ok {
+			var result T
+			if err := json.Unmarshal(entry.Body, &result); err == nil {
+				return result, nil
+			}
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
 	if err != nil {
-		return Forecasts{}, fmt.Errorf("%v: failed to parse forecast URL: %w", gpr.Properties.Forecast, err)
+		return zero, err
 	}
-	frc, buf, _, err := fcep.Get(ctx, up.String())
+	result, body, _, resp, err := ep.IssueRequest(ctx, req)
 	if err != nil {
-		return Forecasts{}, fmt.Errorf("%v: forecast download failed: %w", u.String(), err)
-	}
-	os.WriteFile("forecast.json", buf, 0644)
-	fc := Forecasts{
-		Lat:   lat,
-		Long:  long,
-		GridX: gpr.Properties.X,
-		GridY: gpr.Properties.Y,
-	}
-	fc.Periods = make([]Forecast, len(frc.Properties.Periods))
-	copy(fc.Periods, frc.Properties.Periods)
-	for i, p := range fc.Periods {
-		switch p.ShortForecast {
-		case "Clear", "Sunny":
-			fc.Periods[i].OpaqueCloudCoverage = ClearSunny
-		case "Mostly Clear", "Mostly Sunny":
-			fc.Periods[i].OpaqueCloudCoverage = MostlyClearSunny
-		case "Partly Cloudy", "Partly Sunny":
-			fc.Periods[i].OpaqueCloudCoverage = PartlyCloudySunny
-		case "Mostly Cloudy":
-			fc.Periods[i].OpaqueCloudCoverage = MostlyCloudy
-		case "Cloudy":
-			fc.Periods[i].OpaqueCloudCoverage = Cloudy
-		default:
-			fc.Periods[i].OpaqueCloudCoverage = estimateOpaqueCloudCoverage(p.ShortForecast)
+		return zero, err
+	}
+	if c != nil {
+		c.Set(key, cache.Entry{Body: body, Expires: expiryFromResponse(resp, minTTL)})
+	}
+	return result, nil
+}
+
+// expiryFromResponse returns the time at which a cached response should
+// expire, honoring the Cache-Control max-age and Expires headers when
+// present, with max-age taking precedence over Expires, but never returning
+// a time sooner than minTTL from now.
+func expiryFromResponse(resp *http.Response, minTTL time.Duration) time.Time {
+	now := time.Now()
+	expires := now.Add(minTTL)
+	if resp == nil {
+		return expires
+	}
+	maxAge := false
+	if cc := resp.Header.Get("Cache-Control"); cc != "" {
+		for _, field := range strings.Split(cc, ",") {
+			field = strings.TrimSpace(field)
+			if secs, ok := strings.CutPrefix(field, "max-age="); ok {
+				if n, err := strconv.Atoi(secs); err == nil {
+					maxAge = true
+					if t := now.Add(time.Duration(n) * time.Second); t.After(expires) {
+						expires = t
+					}
+				}
+			}
 		}
 	}
-	return fc, nil
+	if exp := resp.Header.Get("Expires"); !maxAge && exp != "" {
+		if t, err := http.ParseTime(exp); err == nil && t.After(expires) {
+			expires = t
+		}
+	}
+	return expires
 }
 
-func estimateOpaqueCloudCoverage(shortForecast string) OpaqueCloudCoverage {
-	tl := strings.ToLower(shortForecast)
-	if strings.Contains(tl, "rain") {
-		return Rain
+// GetForecast returns the standard, 12-hour granularity, forecast for the
+// specified lat/long.
+func (a *API) GetForecast(ctx context.Context, lat, long float64) (backend.Forecast, error) {
+	gp, err := a.lookupGridPoint(ctx, lat, long)
+	if err != nil {
+		return backend.Forecast{}, err
 	}
-	if strings.Contains(tl, "snow") {
-		return Snow
+	fcep := operations.NewEndpoint[forecastResponse](a.opts...)
+	fcURL := fmt.Sprintf("%s/gridpoints/%s/%d,%d/forecast", a.host, gp.ID, gp.X, gp.Y)
+	key := cache.Key{Lat: lat, Long: long, Endpoint: "forecast"}
+	frc, err := fetchWithCache(ctx, fcep, a.cache, key, a.minTTL, fcURL)
+	if err != nil {
+		return backend.Forecast{}, fmt.Errorf("%v: forecast download failed: %w", fcURL, err)
 	}
-	return UnknownOpaqueCloudCoverage
+	return backend.Forecast{
+		Lat:      lat,
+		Long:     long,
+		Location: gp.toLocation(),
+		Periods:  periodsFrom(frc.Properties.Periods),
+	}, nil
 }
 
-func (fc Forecasts) ForTime(when time.Time) (Forecast, bool) {
-	for _, f := range fc.Periods {
-		if f.StartTime.Before(when) && f.EndTime.After(when) {
-			return f, true
-		}
+// GetHourlyForecast returns the hour granular forecast for the specified
+// lat/long. It returns an error if the gridpoint lookup reports no
+// forecastHourly URL; otherwise it requests the hourly forecast for the
+// looked up grid point from the configured host.
+func (a *API) GetHourlyForecast(ctx context.Context, lat, long float64) (backend.Forecast, error) {
+	gp, err := a.lookupGridPoint(ctx, lat, long)
+	if err != nil {
+		return backend.Forecast{}, err
 	}
-	return Forecast{}, false
+	if gp.Hourly == "" {
+		return backend.Forecast{}, fmt.Errorf("no hourly forecast available for %f,%f", lat, long)
+	}
+	fcep := operations.NewEndpoint[forecastResponse](a.opts...)
+	fcURL := fmt.Sprintf("%s/gridpoints/%s/%d,%d/forecast/hourly", a.host, gp.ID, gp.X, gp.Y)
+	key := cache.Key{Lat: lat, Long: long, Endpoint: "forecast/hourly"}
+	frc, err := fetchWithCache(ctx, fcep, a.cache, key, a.minTTL, fcURL)
+	if err != nil {
+		return backend.Forecast{}, fmt.Errorf("%v: hourly forecast download failed: %w", fcURL, err)
+	}
+	return backend.Forecast{
+		Lat:           lat,
+		Long:          long,
+		Location:      gp.toLocation(),
+		HourlyPeriods: periodsFrom(frc.Properties.Periods),
+	}, nil
+}
+
+// LookupLocation returns the location metadata, including reverse-geocoded
+// city/state, IANA time zone and forecast zone/county identifiers, for the
+// specified lat/long.
+func (a *API) LookupLocation(ctx context.Context, lat, long float64) (backend.Location, error) {
+	gp, err := a.lookupGridPoint(ctx, lat, long)
+	if err != nil {
+		return backend.Location{}, err
+	}
+	return gp.toLocation(), nil
+}
+
+// CloudOpacityFromShortForecast returns the cloud opacity based on the short
+// forecast string.
+func CloudOpacityFromShortForecast(shortForecast string) OpaqueCloudCoverage {
+	return backend.CloudOpacityFromShortForecast(shortForecast)
 }
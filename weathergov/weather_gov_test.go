@@ -17,6 +17,7 @@ import (
 	"time"
 
 	"cloudeng.io/webapi/webapitestutil"
+	"github.com/cosnicolaou/weather/cache"
 	"github.com/cosnicolaou/weather/weathergov"
 )
 
@@ -32,12 +33,23 @@ func writeFile(name string, w http.ResponseWriter) {
 }
 
 func runMock() *httptest.Server {
+	return runCountingMock(nil)
+}
+
+func runCountingMock(count *int) *httptest.Server {
 	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if strings.Contains("points", r.URL.Path) {
-			writeFile("gridpoint.json", w)
+		if count != nil {
+			*count++
 		}
-		if strings.Contains("forecast", r.URL.Path) {
+		switch {
+		case strings.Contains(r.URL.Path, "/points/"):
+			writeFile("gridpoint.json", w)
+		case strings.Contains(r.URL.Path, "/forecast/hourly"):
+			writeFile("forecast_hourly.json", w)
+		case strings.Contains(r.URL.Path, "forecast"):
 			writeFile("forecast.json", w)
+		case strings.Contains(r.URL.Path, "/alerts/active"):
+			writeFile("alerts.json", w)
 		}
 	})
 	return webapitestutil.NewServer(handler)
@@ -61,19 +73,13 @@ func TestLookup(t *testing.T) {
 	if got, want := gp.Long, -97.0892; got != want {
 		t.Errorf("got %v, want %v", got, want)
 	}
-	if got, want := gp.GridX, 32; got != want {
-		t.Errorf("got %v, want %v", got, want)
-	}
-	if got, want := gp.GridY, 81; got != want {
-		t.Errorf("got %v, want %v", got, want)
-	}
 	if got, want := len(gp.Periods), 14; got != want {
 		t.Errorf("got %v, want %v", got, want)
 	}
 
 	start := gp.Periods[0].StartTime
 	end := gp.Periods[0].EndTime
-	if got, want := end.Sub(start), time.Hour*6; got != want {
+	if got, want := end.Sub(start), time.Hour*3; got != want {
 		t.Errorf("got %v, want %v", got, want)
 	}
 	for _, p := range gp.Periods[1:] {
@@ -88,4 +94,271 @@ func TestLookup(t *testing.T) {
 			t.Errorf("unexpected unknown cloud coverage: %q", p.ShortForecast)
 		}
 	}
+
+	first := gp.Periods[0]
+	if got, want := first.Temperature, 14.0; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := first.TemperatureUnit, "F"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := first.WindSpeed, "5 mph"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := first.WindDirection, "NW"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := first.DetailedForecast, "Partly sunny, with a high near 14. Northwest wind around 5 mph."; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := first.DewPoint, -10.555555555555555; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := first.RelativeHumidity, 62.0; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	eighth := gp.Periods[7]
+	if got, want := eighth.ProbabilityOfPrecipitation, 20.0; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	if got, want := gp.Location.City, "Linn"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := gp.Location.State, "KS"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := gp.Location.GridID, "TOP"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := gp.Location.TimeZone, "America/Chicago"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := gp.Location.ForecastZoneID, "KSZ009"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := gp.Location.CountyID, "KSC201"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestLookupLocation(t *testing.T) {
+	ctx := context.Background()
+	srv := runMock()
+	defer srv.Close()
+
+	api := weathergov.NewAPI()
+	api.SetHost(srv.URL)
+	loc, err := api.LookupLocation(ctx, 39.7456, -97.0892)
+	if err != nil {
+		t.Fatalf("failed to lookup location: %v", err)
+	}
+	if got, want := loc.City, "Linn"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := loc.State, "KS"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestHourlyLookup(t *testing.T) {
+	ctx := context.Background()
+	srv := runMock()
+	defer srv.Close()
+
+	api := weathergov.NewAPI()
+	api.SetHost(srv.URL)
+	gp, err := api.GetHourlyForecast(ctx, 39.7456, -97.0892)
+	if err != nil {
+		t.Fatalf("failed to get hourly forecast: %v", err)
+	}
+	if got, want := len(gp.HourlyPeriods), 2; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	for _, p := range gp.HourlyPeriods {
+		if got, want := p.EndTime.Sub(p.StartTime), time.Hour; got != want {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	}
+
+	fc, ok := gp.PeriodFor(gp.HourlyPeriods[1].StartTime)
+	if !ok {
+		t.Fatalf("expected a period to be found")
+	}
+	if got, want := fc.Temperature, gp.HourlyPeriods[1].Temperature; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestActiveAlerts(t *testing.T) {
+	ctx := context.Background()
+	srv := runMock()
+	defer srv.Close()
+
+	alerts := weathergov.NewAlerts()
+	alerts.SetHost(srv.URL)
+	got, err := alerts.GetActiveAlerts(ctx, 39.7456, -97.0892)
+	if err != nil {
+		t.Fatalf("failed to get active alerts: %v", err)
+	}
+	if got, want := len(got), 2; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got, want := got[0].Event, "Tornado Warning"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := got[0].Severity, weathergov.ExtremeSeverity; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := got[1].Severity, weathergov.MinorSeverity; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestForecastCaching(t *testing.T) {
+	ctx := context.Background()
+	var requests int
+	srv := runCountingMock(&requests)
+	defer srv.Close()
+
+	api := weathergov.NewAPI()
+	api.SetHost(srv.URL)
+
+	if _, err := api.GetForecast(ctx, 39.7456, -97.0892); err != nil {
+		t.Fatalf("failed to get forecast: %v", err)
+	}
+	first := requests
+	if first == 0 {
+		t.Fatalf("expected at least one request to be made")
+	}
+
+	if _, err := api.GetForecast(ctx, 39.7456, -97.0892); err != nil {
+		t.Fatalf("failed to get forecast: %v", err)
+	}
+	if got, want := requests, first; got != want {
+		t.Errorf("expected the second lookup to be served from cache: got %v requests, want %v", got, want)
+	}
+}
+
+func TestCacheDefaultTwoTierTTL(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	srv := runMock()
+	defer srv.Close()
+
+	api := weathergov.NewAPI()
+	api.SetHost(srv.URL)
+	if err := api.Configure(map[string]any{"cache_dir": dir}); err != nil {
+		t.Fatalf("failed to configure: %v", err)
+	}
+	if _, err := api.GetForecast(ctx, 39.7456, -97.0892); err != nil {
+		t.Fatalf("failed to get forecast: %v", err)
+	}
+
+	fs := cache.NewFilesystem(dir)
+	forecastEntry, ok := fs.Get(cache.Key{Lat: 39.7456, Long: -97.0892, Endpoint: "forecast"})
+	if !ok {
+		t.Fatalf("expected a cached forecast entry")
+	}
+	gridEntry, ok := fs.Get(cache.Key{Lat: 39.7456, Long: -97.0892, Endpoint: "points"})
+	if !ok {
+		t.Fatalf("expected a cached grid point entry")
+	}
+	// grid point lookups are cached far longer than forecasts by default,
+	// mirroring the NWS API's own two-tier structure of a grid point
+	// lookup followed by a forecast lookup.
+	if !gridEntry.Expires.After(forecastEntry.Expires) {
+		t.Errorf("expected grid point TTL (%v) to exceed forecast TTL (%v)", gridEntry.Expires, forecastEntry.Expires)
+	}
+}
+
+func TestCacheTTLOptionsAndHeaders(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	var forecastHeaders http.Header
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if forecastHeaders != nil && strings.Contains(r.URL.Path, "forecast") {
+			for k, vs := range forecastHeaders {
+				for _, v := range vs {
+					w.Header().Add(k, v)
+				}
+			}
+		}
+		switch {
+		case strings.Contains(r.URL.Path, "/points/"):
+			writeFile("gridpoint.json", w)
+		case strings.Contains(r.URL.Path, "forecast"):
+			writeFile("forecast.json", w)
+		}
+	})
+	srv := webapitestutil.NewServer(handler)
+	defer srv.Close()
+
+	api := weathergov.NewAPI()
+	api.SetHost(srv.URL)
+	if err := api.Configure(map[string]any{
+		"cache_dir":    dir,
+		"min_ttl":      "1m",
+		"grid_min_ttl": "2m",
+	}); err != nil {
+		t.Fatalf("failed to configure: %v", err)
+	}
+
+	forecastHeaders = http.Header{"Cache-Control": []string{"max-age=3600"}}
+	if _, err := api.GetForecast(ctx, 39.7456, -97.0892); err != nil {
+		t.Fatalf("failed to get forecast: %v", err)
+	}
+
+	fs := cache.NewFilesystem(dir)
+	now := time.Now()
+
+	forecastEntry, ok := fs.Get(cache.Key{Lat: 39.7456, Long: -97.0892, Endpoint: "forecast"})
+	if !ok {
+		t.Fatalf("expected a cached forecast entry")
+	}
+	// the response's Cache-Control: max-age=3600 header should extend the
+	// expiry well past the configured 1m min_ttl.
+	if got, want := forecastEntry.Expires, now.Add(30*time.Minute); !got.After(want) {
+		t.Errorf("got %v, want after %v", got, want)
+	}
+
+	gridEntry, ok := fs.Get(cache.Key{Lat: 39.7456, Long: -97.0892, Endpoint: "points"})
+	if !ok {
+		t.Fatalf("expected a cached grid point entry")
+	}
+	// the grid point response carries no Cache-Control header, so its
+	// expiry should fall back to the configured grid_min_ttl.
+	if got, want := gridEntry.Expires, now.Add(90*time.Second); !got.After(want) {
+		t.Errorf("got %v, want after %v", got, want)
+	}
+	if got, want := gridEntry.Expires, now.Add(3*time.Minute); got.After(want) {
+		t.Errorf("got %v, want before %v", got, want)
+	}
+}
+
+func TestWatchAlerts(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	srv := runMock()
+	defer srv.Close()
+
+	alerts := weathergov.NewAlerts()
+	alerts.SetHost(srv.URL)
+	ch := alerts.WatchAlerts(ctx, 39.7456, -97.0892)
+
+	seen := map[string]weathergov.AlertEventKind{}
+	for i := 0; i < 2; i++ {
+		al := <-ch
+		seen[al.ID] = al.Kind
+	}
+	cancel()
+	for range ch {
+	}
+	for _, kind := range seen {
+		if got, want := kind, weathergov.AlertNew; got != want {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	}
 }